@@ -0,0 +1,104 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consistenthash
+
+import (
+	"math"
+	"sort"
+)
+
+// defaultBoundedLoadFactor是GetBounded在BoundedLoadFactor未设置时使用的默认值c
+const defaultBoundedLoadFactor = 1.25
+
+// GetBounded实现Mirrokni/Thorup/Zadimoghaddam提出的"consistent hashing with
+// bounded loads"：从Get选出的位置开始沿环顺序查找，只要某个候选节点当前负载
+// 小于ceil(c*平均负载)就返回它，否则顺着环前进到下一个虚拟节点继续尝试，
+// 环上所有虚拟节点最多遍历一圈。load由调用方维护，本方法不修改、也不持有它，
+// 因此groupcache的peer选择逻辑可以在不改变环本身的情况下接入负载均衡。
+func (m *Map) GetBounded(key string, load map[string]int64) string {
+	if m.jump {
+		// jump模式没有环结构可供环绕查找，退化为普通的Get
+		return m.getJump(key)
+	}
+	if m.IsEmpty() || len(m.nodes) == 0 {
+		return ""
+	}
+
+	c := m.BoundedLoadFactor
+	if c <= 1 {
+		c = defaultBoundedLoadFactor
+	}
+
+	var total int64
+	for _, l := range load {
+		total += l
+	}
+	avg := float64(total) / float64(len(m.nodes))
+	capacity := int64(math.Ceil(c * avg))
+
+	idx := m.search(key)
+	for i := 0; i < len(m.keys); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if load[node] < capacity {
+			return node
+		}
+	}
+	// 所有节点都已达到上限时（例如load总和为0、上限因此为0，或load中含有不在环上
+	// 的节点），兜底返回Get本来会选出的节点
+	return m.hashMap[m.keys[idx]]
+}
+
+// GetN返回环上从key对应位置开始、顺序排列的n个不同节点，用于需要多个候选
+// 副本的场景（例如replication/failover，或是GetBounded的上层调用方想在
+// 首选节点过载时自行挑选下一个）。环上不同节点不足n个时返回全部节点；
+// jump模式下没有环，最多只返回Get选出的那一个节点
+func (m *Map) GetN(key string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	if m.jump {
+		if node := m.Get(key); node != "" {
+			return []string{node}
+		}
+		return nil
+	}
+	if m.IsEmpty() {
+		return nil
+	}
+
+	idx := m.search(key)
+	seen := make(map[string]bool, n)
+	nodes := make([]string, 0, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// search是Get中二分查找逻辑的提取版本，返回key在环上对应的虚拟节点下标
+func (m *Map) search(key string) int {
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] >= hash })
+	if idx == len(m.keys) {
+		idx = 0
+	}
+	return idx
+}
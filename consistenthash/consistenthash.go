@@ -26,10 +26,22 @@ import (
 type Hash func(data []byte) uint32
 
 type Map struct {
-	hash     Hash           // 哈希算法
-	replicas int            // 副本数量，其中副本即为虚拟节点
-	keys     []int          // key，即为服务器的得到的hash key值，scice为升序排列。
-	hashMap  map[int]string // 哈希表，即缓存服务器到key的一个映射
+	hash     Hash                // 哈希算法
+	replicas int                 // 副本数量，其中副本即为虚拟节点
+	keys     []int               // key，即为服务器的得到的hash key值，scice为升序排列。
+	hashMap  map[int]string      // 哈希表，即缓存服务器到key的一个映射
+	nodes    map[string]struct{} // 环上现存的节点集合，用于计算GetBounded的平均负载
+
+	// BoundedLoadFactor是GetBounded使用的负载上限系数c（节点允许的最大负载为
+	// ceil(c*平均负载)），需要大于1，零值时使用默认的1.25
+	BoundedLoadFactor float64
+
+	// 以下字段仅用于NewJump构造的Map，此时不再使用上面的虚拟节点环，
+	// 而是使用Jump Consistent Hash对固定数量的bucket做映射。
+	jump        bool     // 是否为jump模式
+	numBuckets  int      // NewJump构造时传入的预期bucket总数，仅作记录，不参与jumpHash运算与查找（见getJump）
+	bucketEnds  []int    // 每个节点所占bucket区间的右端点（不含），与bucketNodes一一对应，递增排列
+	bucketNodes []string // 每个区间对应的节点名称
 }
 
 // 本函数用于新生成一个一致性哈希map
@@ -38,6 +50,7 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		hash:     fn, // 哈希函数可以自己制定
 		hashMap:  make(map[int]string),
+		nodes:    make(map[string]struct{}),
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE // 如果输入没有哈希函数的话，则可使用默认哈希函数
@@ -59,28 +72,39 @@ func (m *Map) Add(keys ...string) {
 			m.keys = append(m.keys, hash) // 将计算出来的hash增加至keys的slice中
 			m.hashMap[hash] = key         // 增加hash <-> key的一个映射
 		}
+		m.nodes[key] = struct{}{}
 	}
 	sort.Ints(m.keys) // 重新排列
 }
 
+// 从哈希环中删除某一节点（及其所有虚拟节点）
+func (m *Map) Remove(key string) {
+	for i := 0; i < m.replicas; i++ {
+		// 计算出该节点每个副本对应的哈希值，与Add中的计算方式保持一致
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		delete(m.hashMap, hash) // 从哈希表中删除该副本
+
+		// 在keys中找到该哈希值所在位置并删除，keys始终保持升序排列
+		idx := sort.SearchInts(m.keys, hash)
+		if idx < len(m.keys) && m.keys[idx] == hash {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+		}
+	}
+	delete(m.nodes, key)
+}
+
 // 从哈希环中找到最合适的一个缓存节点
 func (m *Map) Get(key string) string {
+	// jump模式下没有虚拟节点环，走jumpHash查找
+	if m.jump {
+		return m.getJump(key)
+	}
+
 	// 首先判断是否为空，如果为空则返回空
 	if m.IsEmpty() {
 		return ""
 	}
 
-	// 使用同一哈希算法计算出key的对应哈希值
-	hash := int(m.hash([]byte(key)))
-
-	// 使用二分查找找到最合适的节点
-	idx := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] >= hash })
-
-	// 如果没有找到，即到达了环末尾，则返回首节点即可
-	if idx == len(m.keys) {
-		idx = 0
-	}
-
 	// 返回对应的节点名称
-	return m.hashMap[m.keys[idx]]
+	return m.hashMap[m.keys[m.search(key)]]
 }
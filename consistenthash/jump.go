@@ -0,0 +1,105 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consistenthash
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// 本函数用于新生成一个使用Jump Consistent Hash的Map，与New不同，
+// 这里不再维护一个虚拟节点环，而是把key直接映射到[0, buckets)中的某个bucket，
+// 因此不再有O(R·N)的内存占用，Get的时间复杂度也从O(log(R·N))降为O(log buckets)。
+// 节点与bucket的对应关系需要调用AddWeighted建立，buckets只是预期的权重总和，
+// 仅被记录在Map中，查找时不会读取它；真正参与jumpHash运算的bucket总数始终是
+// AddWeighted调用至今的权重累计值（见getJump），即便调用方传入的buckets与
+// 实际权重总和不一致，也不会出现把一整段bucket都错误地压到最后一个节点上。
+func NewJump(buckets int, fn Hash) *Map {
+	m := &Map{
+		hash:       fn,
+		jump:       true,
+		numBuckets: buckets,
+	}
+	if m.hash == nil {
+		m.hash = crc32.ChecksumIEEE
+	}
+	return m
+}
+
+// 按权重向jump模式的Map中增加一个节点，权重越大，分配到的bucket区间越长，
+// 被选中的概率也就越大。多次调用会把节点依次追加到bucket区间末尾。
+func (m *Map) AddWeighted(node string, weight int) {
+	if !m.jump || weight <= 0 {
+		return
+	}
+	end := weight
+	if n := len(m.bucketEnds); n > 0 {
+		end += m.bucketEnds[n-1]
+	}
+	m.bucketEnds = append(m.bucketEnds, end)
+	m.bucketNodes = append(m.bucketNodes, node)
+}
+
+// jumpHash实现Lamping/Veach提出的Jump Consistent Hash算法，
+// 给定64位的key哈希值与bucket总数numBuckets，在O(log numBuckets)内
+// 计算出该key应落入的bucket，且无需保存任何环状结构。
+func jumpHash(key uint64, numBuckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// getJump是Get在jump模式下的实现：先用jumpHash选出bucket，
+// 再在bucketEnds中二分查找该bucket归属的节点。
+// jumpHash始终按bucketEnds当前的权重总和（而不是NewJump构造时传入的buckets）
+// 计算bucket总数：只要AddWeighted建立的区间覆盖了[0, total)，jumpHash选出的
+// bucket就必然落在某个真实节点的区间内，SearchInts也就不会越界到len(bucketEnds)、
+// 不需要再靠clamp把多余的bucket都归给最后一个节点，从而避免权重总和与buckets
+// 不一致时把一大段key静默地压到最后添加的节点上。
+func (m *Map) getJump(key string) string {
+	if len(m.bucketEnds) == 0 {
+		return ""
+	}
+	total := m.bucketEnds[len(m.bucketEnds)-1]
+	hash := mix64(m.hash([]byte(key)))
+	bucket := jumpHash(hash, total)
+
+	idx := sort.SearchInts(m.bucketEnds, bucket+1)
+	if idx == len(m.bucketEnds) {
+		idx = len(m.bucketEnds) - 1
+	}
+	return m.bucketNodes[idx]
+}
+
+// mix64把m.hash给出的32位哈希值（默认是crc32，也可能是调用方传入的其他
+// Hash实现）雪崩扩散成64位，使其高32位不再恒为0：jumpHash需要一个64位key，
+// 直接做uint64(h)会让它的LCG混合只在低32位上游走，分布质量达不到算法假设的
+// 水平。这里用MurmurHash3的fmix64终拌函数，不引入额外的真实熵，但能让
+// 这32位的熵均匀散布到全部64位上。
+func mix64(h uint32) uint64 {
+	x := uint64(h)
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
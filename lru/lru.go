@@ -17,7 +17,28 @@ limitations under the License.
 // LRU算法
 package lru
 
-import "container/list"
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// EvictionReason说明一次淘汰是由什么原因触发的，会透传给OnEvicted
+type EvictionReason int
+
+const (
+	EvictLRU      EvictionReason = iota // 调用方主动Remove/Clear，非容量触发
+	EvictTTL                            // 条目已过期
+	EvictCapacity                       // 因容量超限而淘汰（含严格LRU、近似LRU采样、policy-victim三种选法）
+)
+
+// SamplePolicy用于配置近似LRU淘汰，参考了Redis的maxmemory-samples思路：
+// 开启后RemoveOldest不再固定淘汰链表尾部，而是从cache中随机取样Sample个
+// 条目，淘汰其中最久未被访问的一个，从而不必在每次Get时都维护严格的链表顺序。
+type SamplePolicy struct {
+	Enabled bool // 是否启用采样淘汰
+	Sample  int  // 每次淘汰时取样的条目数，<=0时使用默认值5
+}
 
 // Cache is an LRU cache. It is not safe for concurrent access.
 type Cache struct {
@@ -25,8 +46,15 @@ type Cache struct {
 	// 如果为0的话，则表示不做限制
 	MaxEntries int
 
-	// 销毁前回调函数
-	OnEvicted func(key Key, value interface{})
+	// 近似LRU采样淘汰策略，零值表示使用严格LRU（默认行为）
+	SamplePolicy SamplePolicy
+
+	// 可插拔的淘汰策略，nil表示使用上面SamplePolicy描述的内置LRU/近似LRU逻辑。
+	// 一般通过NewWithPolicy设置，见policy.go
+	EvictionPolicy Policy
+
+	// 销毁前回调函数，reason说明本次淘汰的触发原因
+	OnEvicted func(key Key, value interface{}, reason EvictionReason)
 
 	// 链表
 	ll *list.List
@@ -42,6 +70,13 @@ type Key interface{}
 type entry struct {
 	key   Key
 	value interface{}
+
+	expiresAt  time.Time // 过期时间，零值表示永不过期
+	lastAccess time.Time // 最近一次访问时间，供采样淘汰比较新旧
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
 }
 
 // 本函数用于初始化一个Cache
@@ -53,22 +88,57 @@ func New(maxEntries int) *Cache {
 	}
 }
 
-// 向缓存中新增某一元素
+// 向缓存中新增某一元素，不设置过期时间
 func (c *Cache) Add(key Key, value interface{}) {
+	c.add(key, value, time.Time{})
+}
+
+// 向缓存中新增某一元素，ttl过后该元素在下一次Get或janitor扫描时失效
+func (c *Cache) AddWithTTL(key Key, value interface{}, ttl time.Duration) {
+	c.add(key, value, time.Now().Add(ttl))
+}
+
+func (c *Cache) add(key Key, value interface{}, expiresAt time.Time) {
 	// 如果缓存为空的话，则重新申请map以及list
 	if c.cache == nil {
 		c.cache = make(map[interface{}]*list.Element)
 		c.ll = list.New()
 	}
+	now := time.Now()
 	// 判断key是否已经存在于cache中，如果是，则将其移动至列表头部
 	if ee, ok := c.cache[key]; ok {
 		c.ll.MoveToFront(ee)
-		ee.Value.(*entry).value = value // 设置key的value
+		en := ee.Value.(*entry)
+		en.value = value
+		en.expiresAt = expiresAt
+		en.lastAccess = now
+		if c.EvictionPolicy != nil {
+			c.EvictionPolicy.OnHit(key)
+		}
 		return
 	}
+
+	// 对于带准入控制的策略（如TinyLFU），先判断这个新key是否值得替换掉
+	// 当前链表尾部的条目，不值得的话直接丢弃，只让策略感知到这次访问即可。
+	// 启用SamplePolicy时，随后真正被淘汰的是采样选出的条目，未必是这个链表尾部
+	if c.MaxEntries != 0 && c.ll.Len() >= c.MaxEntries {
+		if ap, ok := c.EvictionPolicy.(AdmissionPolicy); ok {
+			if back := c.ll.Back(); back != nil {
+				victim := back.Value.(*entry).key
+				if !ap.Admit(key, victim) {
+					ap.OnAdd(key)
+					return
+				}
+			}
+		}
+	}
+
 	// 如果key在list中不存在的话，则新建一个entry，并且将其插入链表头部
-	ele := c.ll.PushFront(&entry{key, value})
+	ele := c.ll.PushFront(&entry{key: key, value: value, expiresAt: expiresAt, lastAccess: now})
 	c.cache[key] = ele // 并且在cache中存放
+	if c.EvictionPolicy != nil {
+		c.EvictionPolicy.OnAdd(key)
+	}
 
 	// 如果缓存此时已经满了，则需要对其进行淘汰
 	// 使用RemoveOldest函数淘汰掉最久没有被使用的缓存
@@ -77,20 +147,31 @@ func (c *Cache) Add(key Key, value interface{}) {
 	}
 }
 
-// 从缓存中获取一个value
+// 从缓存中获取一个value，若对应条目已过期则视为未命中，并按EvictTTL淘汰
 func (c *Cache) Get(key Key) (value interface{}, ok bool) {
 	// 为空测返回空
 	if c.cache == nil {
 		return
 	}
-	// 是否hit，如果hit则需要
-	// 1. 将该缓存移动至list的头部
-	// 2. 返回该key对应的value
-	if ele, hit := c.cache[key]; hit {
+	ele, hit := c.cache[key]
+	if !hit {
+		return
+	}
+	en := ele.Value.(*entry)
+	now := time.Now()
+	if en.expired(now) {
+		c.removeElement(ele, EvictTTL)
+		return nil, false
+	}
+	en.lastAccess = now
+	if c.EvictionPolicy != nil {
+		c.EvictionPolicy.OnHit(key)
+	}
+	// 开启采样淘汰后不再需要维护严格的访问顺序，省去MoveToFront的链表调整开销
+	if !c.SamplePolicy.Enabled {
 		c.ll.MoveToFront(ele)
-		return ele.Value.(*entry).value, true
 	}
-	return
+	return en.value, true
 }
 
 // 本函数用于删除key对应的cache
@@ -100,31 +181,78 @@ func (c *Cache) Remove(key Key) {
 	}
 	// 使用removeElement函数删除cache中对应项
 	if ele, hit := c.cache[key]; hit {
-		c.removeElement(ele)
+		c.removeElement(ele, EvictLRU)
 	}
 }
 
-// 本函数用于淘汰最久未被使用的缓存
-// 1. 从list中获取最久未被使用的对应cache
-// 2. 利用removeElement函数淘汰对应cache
+// 本函数用于淘汰最久未被使用的缓存。
+// 若设置了EvictionPolicy且策略给出了明确的Victim，则按该策略淘汰（如LFU）；
+// 像TinyLFU这类只做准入控制、不接管淘汰顺序的策略，Victim会返回nil，
+// 此时退化为下面的内置逻辑：默认严格按链表尾部（即最久未使用）淘汰，
+// 若启用了SamplePolicy.Enabled，则改为从cache中随机取样SamplePolicy.Sample
+// 个条目，淘汰最久未访问的一个
 func (c *Cache) RemoveOldest() {
 	if c.cache == nil {
 		return
 	}
+	if c.EvictionPolicy != nil {
+		if victim := c.EvictionPolicy.Victim(); victim != nil {
+			if ele, ok := c.cache[victim]; ok {
+				c.removeElement(ele, EvictCapacity)
+				return
+			}
+		}
+	}
+	if c.SamplePolicy.Enabled {
+		c.removeOldestSampled()
+		return
+	}
 	ele := c.ll.Back()
 	if ele != nil {
-		c.removeElement(ele)
+		// 链表尾部即是容量超限时的淘汰候选，与removeOldestSampled、
+		// policy-victim两条路径保持一致，统一标记为EvictCapacity，
+		// EvictLRU只保留给Remove这类非容量触发的淘汰
+		c.removeElement(ele, EvictCapacity)
+	}
+}
+
+// removeOldestSampled按Redis近似LRU的思路，从cache中取样若干条目后
+// 淘汰其中lastAccess最早的一个；Go的map遍历顺序本身是随机的，足以充当取样
+func (c *Cache) removeOldestSampled() {
+	k := c.SamplePolicy.Sample
+	if k <= 0 {
+		k = 5
+	}
+	var oldest *list.Element
+	var oldestAccess time.Time
+	sampled := 0
+	for _, ele := range c.cache {
+		en := ele.Value.(*entry)
+		if oldest == nil || en.lastAccess.Before(oldestAccess) {
+			oldest = ele
+			oldestAccess = en.lastAccess
+		}
+		sampled++
+		if sampled >= k {
+			break
+		}
+	}
+	if oldest != nil {
+		c.removeElement(oldest, EvictCapacity)
 	}
 }
 
 // 本函数用于淘汰缓存
 // 即需要删除链表中对应节点，以及cache中对应项
-func (c *Cache) removeElement(e *list.Element) {
+func (c *Cache) removeElement(e *list.Element, reason EvictionReason) {
 	c.ll.Remove(e)
 	kv := e.Value.(*entry)
 	delete(c.cache, kv.key)
+	if c.EvictionPolicy != nil {
+		c.EvictionPolicy.OnRemove(kv.key)
+	}
 	if c.OnEvicted != nil {
-		c.OnEvicted(kv.key, kv.value)
+		c.OnEvicted(kv.key, kv.value, reason)
 	}
 }
 
@@ -138,12 +266,66 @@ func (c *Cache) Len() int {
 
 // 清除所有缓存
 func (c *Cache) Clear() {
-	if c.OnEvicted != nil {
+	if c.OnEvicted != nil || c.EvictionPolicy != nil {
 		for _, e := range c.cache {
 			kv := e.Value.(*entry)
-			c.OnEvicted(kv.key, kv.value)
+			if c.EvictionPolicy != nil {
+				c.EvictionPolicy.OnRemove(kv.key)
+			}
+			if c.OnEvicted != nil {
+				c.OnEvicted(kv.key, kv.value, EvictLRU)
+			}
 		}
 	}
 	c.ll = nil
 	c.cache = nil
 }
+
+// StartJanitor启动一个后台goroutine，每隔interval扫描一小部分随机条目
+// （取样个数同样由SamplePolicy.Sample决定，默认5个）并主动淘汰其中已过期的条目，
+// 避免冷门的过期key一直占着内存直到被Get命中。返回的stop函数用于停止该goroutine。
+// Cache本身不是并发安全的，而janitor的扫描（sweepExpired）会读写c.cache/c.ll，
+// 因此调用方必须传入一把locker：janitor每次扫描前后都会Lock/Unlock它，调用方
+// 自己的Get/Add/Remove也必须持有同一把锁，才能与janitor互斥（见ShardedCache.
+// StartJanitor，它为每个分片传入分片自己的互斥锁）。如果确定不会有其他
+// goroutine并发访问这个Cache，可以传入一个独占的&sync.Mutex{}。
+func (c *Cache) StartJanitor(interval time.Duration, locker sync.Locker) (stop func()) {
+	stopCh := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				locker.Lock()
+				c.sweepExpired()
+				locker.Unlock()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return func() { close(stopCh) }
+}
+
+// sweepExpired扫描一小部分随机条目，主动淘汰其中已经过期的
+func (c *Cache) sweepExpired() {
+	if c.cache == nil {
+		return
+	}
+	k := c.SamplePolicy.Sample
+	if k <= 0 {
+		k = 5
+	}
+	now := time.Now()
+	scanned := 0
+	for _, ele := range c.cache {
+		if ele.Value.(*entry).expired(now) {
+			c.removeElement(ele, EvictTTL)
+		}
+		scanned++
+		if scanned >= k {
+			break
+		}
+	}
+}
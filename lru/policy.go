@@ -0,0 +1,93 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+// Policy是可插拔的淘汰策略接口。EvictionPolicy为nil时Cache使用写在
+// RemoveOldest中的内置LRU/近似LRU逻辑，该逻辑不是本接口的一个实现；
+// 用户可以实现自己的策略（如LFU、TinyLFU、甚至SIEVE）并通过NewWithPolicy
+// 接入，而不必fork整个Cache。
+type Policy interface {
+	// OnAdd在一个新key被物理插入Cache时调用；对AdmissionPolicy，被Admit
+	// 拒绝、未插入Cache的新key也会调用一次
+	OnAdd(key Key)
+	// OnHit在一个已存在的key被访问（Get命中或重复Add）时调用
+	OnHit(key Key)
+	// OnRemove在一个key因任何原因（Remove、TTL过期、Clear，以及容量淘汰
+	// 本身）从Cache中移除时调用，让策略清理与该key相关的状态，避免
+	// Victim之外的移除路径导致策略内部的统计数据永久泄漏、与Cache失配
+	OnRemove(key Key)
+	// Victim返回该策略认为最应该被淘汰的key；如果该策略不接管淘汰顺序
+	// （例如只做准入控制的TinyLFU），可以返回nil，Cache会退回到内置的
+	// LRU/近似LRU逻辑选择victim
+	Victim() Key
+}
+
+// AdmissionPolicy是Policy的一个可选扩展：在缓存已满、即将插入一个全新key时，
+// Cache会额外询问Admit，只有返回true才会真正腾出位置插入，否则直接丢弃这次
+// 写入（但仍然调用OnAdd，让策略感知到这次访问）。TinyLFU就是这种准入过滤器。
+type AdmissionPolicy interface {
+	Policy
+	// Admit判断candidate是否比当前的淘汰候选victim更值得被缓存
+	Admit(candidate, victim Key) bool
+}
+
+// NewWithPolicy创建一个使用自定义淘汰策略p的Cache，maxEntries含义与New相同
+func NewWithPolicy(maxEntries int, p Policy) *Cache {
+	c := New(maxEntries)
+	c.EvictionPolicy = p
+	return c
+}
+
+// LFUPolicy是一个简单的计数式LFU（Least Frequently Used）策略：
+// 为每个key维护一个访问频次计数器，Victim时线性扫描出计数最小的一个。
+type LFUPolicy struct {
+	freq map[Key]int
+}
+
+// NewLFUPolicy创建一个空的LFUPolicy
+func NewLFUPolicy() *LFUPolicy {
+	return &LFUPolicy{freq: make(map[Key]int)}
+}
+
+func (p *LFUPolicy) OnAdd(key Key) {
+	p.freq[key] = 1
+}
+
+func (p *LFUPolicy) OnHit(key Key) {
+	p.freq[key]++
+}
+
+// OnRemove清除key的计数，使Remove/TTL过期/Clear等非Victim路径的移除
+// 也能被freq感知，避免计数表随churn无限增长
+func (p *LFUPolicy) OnRemove(key Key) {
+	delete(p.freq, key)
+}
+
+// Victim返回当前计数最小的key，并将其从计数表中移除（它即将被淘汰）
+func (p *LFUPolicy) Victim() Key {
+	var victim Key
+	min := -1
+	for k, f := range p.freq {
+		if min == -1 || f < min {
+			min, victim = f, k
+		}
+	}
+	if victim != nil {
+		delete(p.freq, victim)
+	}
+	return victim
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedCache是对Cache的一个可选的分片封装，参考了bigcache的分段思路：
+// 将整个缓存拆分为shards个互相独立的Cache，每个Cache由自己的锁保护，
+// key按hash(key) & (shards-1)路由到固定的分片上，从而避免单把大锁
+// 在高并发Get/Add下成为瓶颈。
+type ShardedCache struct {
+	shards []*cacheShard
+	mask   uint64
+	hash   func(Key) uint64
+
+	evictions uint64 // 所有分片因容量超限或TTL过期被动淘汰的总数，原子累加
+}
+
+type cacheShard struct {
+	mu sync.Mutex
+	c  *Cache
+}
+
+// Stats记录ShardedCache的运行统计信息
+type Stats struct {
+	Evictions uint64 // 所有分片因容量超限或TTL过期被动淘汰的条目总数，不含Remove/Clear
+}
+
+// NewSharded创建一个拥有shards个分片的ShardedCache，maxEntries为总容量上限，
+// 按分片数平均分配（向上取整），shards会被向上取整为2的幂，以便用位运算路由。
+// hash为nil时使用fnv64a作为默认哈希算法。
+func NewSharded(maxEntries, shards int, hash func(Key) uint64) *ShardedCache {
+	if shards <= 0 {
+		shards = 1
+	}
+	shards = nextPowerOfTwo(shards)
+
+	perShard := 0
+	if maxEntries > 0 {
+		perShard = (maxEntries + shards - 1) / shards // ceil(total/shards)
+	}
+
+	sc := &ShardedCache{
+		shards: make([]*cacheShard, shards),
+		mask:   uint64(shards - 1),
+		hash:   hash,
+	}
+	if sc.hash == nil {
+		sc.hash = fnv64a
+	}
+	for i := range sc.shards {
+		s := &cacheShard{c: New(perShard)}
+		s.c.OnEvicted = func(key Key, value interface{}, reason EvictionReason) {
+			// Evictions只统计真正"被动挤出"的条目（容量超限或TTL过期），
+			// 调用方主动Remove或Clear不计入，否则该计数器就无法反映
+			// 缓存本身的淘汰压力
+			if reason == EvictCapacity || reason == EvictTTL {
+				atomic.AddUint64(&sc.evictions, 1)
+			}
+		}
+		sc.shards[i] = s
+	}
+	return sc
+}
+
+// 根据key定位其所属的分片
+func (sc *ShardedCache) shardFor(key Key) *cacheShard {
+	return sc.shards[sc.hash(key)&sc.mask]
+}
+
+// Add向对应分片中新增一个元素
+func (sc *ShardedCache) Add(key Key, value interface{}) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Add(key, value)
+}
+
+// Get从对应分片中获取一个value
+func (sc *ShardedCache) Get(key Key) (value interface{}, ok bool) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Get(key)
+}
+
+// Remove从对应分片中删除key
+func (sc *ShardedCache) Remove(key Key) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Remove(key)
+}
+
+// Len返回所有分片的元素总数
+func (sc *ShardedCache) Len() int {
+	total := 0
+	for _, s := range sc.shards {
+		s.mu.Lock()
+		total += s.c.Len()
+		s.mu.Unlock()
+	}
+	return total
+}
+
+// Clear逐个清空所有分片
+func (sc *ShardedCache) Clear() {
+	for _, s := range sc.shards {
+		s.mu.Lock()
+		s.c.Clear()
+		s.mu.Unlock()
+	}
+}
+
+// Stats返回当前的统计信息
+func (sc *ShardedCache) Stats() Stats {
+	return Stats{Evictions: atomic.LoadUint64(&sc.evictions)}
+}
+
+// StartJanitor为每个分片各自启动一个Cache.StartJanitor，并把该分片自己的
+// 互斥锁传给它，使janitor的扫描与Get/Add/Remove互斥，从而可以安全地在
+// ShardedCache上开启TTL被动淘汰之外的主动淘汰。返回的stop函数会停止所有分片
+// 的janitor goroutine。
+func (sc *ShardedCache) StartJanitor(interval time.Duration) (stop func()) {
+	stops := make([]func(), len(sc.shards))
+	for i, s := range sc.shards {
+		stops[i] = s.c.StartJanitor(interval, &s.mu)
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// nextPowerOfTwo返回不小于n的最小2的幂
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fnv64a是默认的分片哈希算法，避免对Key的具体类型做假设，
+// 这里将Key格式化为字符串后再做哈希
+func fnv64a(key Key) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	s, ok := key.(string)
+	if !ok {
+		s = fmt.Sprint(key)
+	}
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return h
+}
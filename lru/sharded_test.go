@@ -0,0 +1,77 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"strconv"
+	"testing"
+)
+
+// TestShardedCacheStatsCountsCapacityEvictions验证纯粹由容量超限触发的淘汰
+// （不调用Remove/Clear）会被Stats().Evictions计入：单分片、单调递增的key，
+// 每次Add都会在MaxEntries处挤出最旧的一个，淘汰次数应等于Add次数减容量。
+func TestShardedCacheStatsCountsCapacityEvictions(t *testing.T) {
+	const maxEntries = 8
+	sc := NewSharded(maxEntries, 1, nil)
+
+	const adds = 1000
+	for i := 0; i < adds; i++ {
+		sc.Add(strconv.Itoa(i), i)
+	}
+
+	want := uint64(adds - maxEntries)
+	if got := sc.Stats().Evictions; got != want {
+		t.Fatalf("Stats().Evictions = %d, want %d", got, want)
+	}
+}
+
+// BenchmarkParallelSharded用b.RunParallel压测ShardedCache在并发Get/Add下
+// 随shards数量增加的扩展性，对照组BenchmarkParallelSingleShard固定shards=1，
+// 相当于单把大锁保护一个Cache，二者对比即可看出分片是否真的缓解了锁竞争。
+func BenchmarkParallelSharded(b *testing.B) {
+	for _, shards := range []int{1, 4, 16, 64} {
+		b.Run(strconv.Itoa(shards), func(b *testing.B) {
+			benchmarkParallelSharded(b, shards)
+		})
+	}
+}
+
+func benchmarkParallelSharded(b *testing.B, shards int) {
+	sc := NewSharded(1<<16, shards, nil)
+	for i := 0; i < 1<<12; i++ {
+		sc.Add(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := strconv.Itoa(i % (1 << 12))
+			if i%8 == 0 {
+				sc.Add(key, i)
+			} else {
+				sc.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkParallelSingleShard是上面的对照组，等价于shards=1
+func BenchmarkParallelSingleShard(b *testing.B) {
+	benchmarkParallelSharded(b, 1)
+}
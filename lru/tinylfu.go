@@ -0,0 +1,215 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+// TinyLFUPolicy是一个准入过滤器（AdmissionPolicy），而不是完整的淘汰策略：
+// 它不接管淘汰顺序（Victim始终返回nil，由Cache退回内置LRU逻辑选择淘汰候选），
+// 只是在缓存满、即将插入一个全新key时，比较该key与当前淘汰候选的估计访问频次，
+// 频次更高才允许替换，否则直接丢弃这次写入。这对groupcache常见的扫描型
+// 工作负载（大量只访问一次的冷key）能显著提升命中率，代价是一个很小的
+// 4-bit Count-Min Sketch加一个doorkeeper布隆过滤器。
+type TinyLFUPolicy struct {
+	capacity  int
+	additions int
+	sketch    *countMinSketch4
+	door      *bloomFilter
+}
+
+// NewTinyLFUPolicy创建一个TinyLFUPolicy，sketch宽度为4*capacity
+func NewTinyLFUPolicy(capacity int) *TinyLFUPolicy {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	width := capacity * 4
+	return &TinyLFUPolicy{
+		capacity: capacity,
+		sketch:   newCountMinSketch4(width),
+		door:     newBloomFilter(width),
+	}
+}
+
+func (p *TinyLFUPolicy) OnAdd(key Key) { p.record(key) }
+func (p *TinyLFUPolicy) OnHit(key Key) { p.record(key) }
+
+// OnRemove无需做任何事：sketch与doorkeeper都是近似、不可删除的概率结构，
+// 本来就会随halve/reset周期性老化，不为单个key维护可精确删除的状态
+func (p *TinyLFUPolicy) OnRemove(key Key) {}
+
+// record给sketch加一次计数。一个key第一次出现时只记录进doorkeeper，
+// 不污染sketch；只有再次出现才真正计入频次，这是TinyLFU论文里
+// 过滤"只访问一次"的key的常规做法
+func (p *TinyLFUPolicy) record(key Key) {
+	h := fnv64a(key)
+	if !p.door.testAndSet(h) {
+		return
+	}
+	p.sketch.increment(h)
+	p.additions++
+	if p.additions >= p.capacity*10 {
+		p.sketch.halve()
+		p.door.reset()
+		p.additions = 0
+	}
+}
+
+func (p *TinyLFUPolicy) estimate(key Key) int {
+	h := fnv64a(key)
+	freq := p.sketch.estimate(h)
+	if p.door.test(h) {
+		freq++
+	}
+	return freq
+}
+
+// Victim始终返回nil：TinyLFU只做准入控制，真正的淘汰顺序交给Cache内置的
+// LRU（或近似LRU采样）逻辑决定
+func (p *TinyLFUPolicy) Victim() Key { return nil }
+
+// Admit比较candidate与victim的估计频次，只有candidate更高才允许替换victim
+func (p *TinyLFUPolicy) Admit(candidate, victim Key) bool {
+	return p.estimate(candidate) > p.estimate(victim)
+}
+
+// countMinSketch4是一个4-bit计数器的Count-Min Sketch，depth固定为4行，
+// 每行使用不同的种子对key的哈希值做二次哈希，estimate取4行中的最小值
+type countMinSketch4 struct {
+	width    int
+	counters [][]byte // 4行，每字节打包2个4-bit计数器
+}
+
+const cmsDepth = 4
+
+var cmsSeeds = [cmsDepth]uint64{
+	0x9E3779B97F4A7C15, 0xC2B2AE3D27D4EB4F, 0x165667B19E3779F9, 0x27D4EB2F165667C5,
+}
+
+func newCountMinSketch4(width int) *countMinSketch4 {
+	if width <= 0 {
+		width = 1
+	}
+	rows := make([][]byte, cmsDepth)
+	for i := range rows {
+		rows[i] = make([]byte, (width+1)/2) // 每字节存2个4-bit计数器
+	}
+	return &countMinSketch4{width: width, counters: rows}
+}
+
+func (s *countMinSketch4) index(row int, h uint64) (byteIdx int, high bool) {
+	mixed := (h ^ cmsSeeds[row]) * 2862933555777941757
+	slot := int(mixed % uint64(s.width))
+	return slot / 2, slot%2 == 1
+}
+
+func (s *countMinSketch4) get(row int, h uint64) byte {
+	byteIdx, high := s.index(row, h)
+	b := s.counters[row][byteIdx]
+	if high {
+		return b >> 4
+	}
+	return b & 0x0F
+}
+
+func (s *countMinSketch4) set(row int, h uint64, v byte) {
+	if v > 0x0F {
+		v = 0x0F
+	}
+	byteIdx, high := s.index(row, h)
+	b := s.counters[row][byteIdx]
+	if high {
+		s.counters[row][byteIdx] = (b & 0x0F) | (v << 4)
+	} else {
+		s.counters[row][byteIdx] = (b & 0xF0) | v
+	}
+}
+
+func (s *countMinSketch4) increment(h uint64) {
+	for row := 0; row < cmsDepth; row++ {
+		if v := s.get(row, h); v < 0x0F {
+			s.set(row, h, v+1)
+		}
+	}
+}
+
+func (s *countMinSketch4) estimate(h uint64) int {
+	min := -1
+	for row := 0; row < cmsDepth; row++ {
+		v := int(s.get(row, h))
+		if min == -1 || v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// halve将所有计数器减半，用于周期性老化，避免sketch随着时间饱和
+func (s *countMinSketch4) halve() {
+	for row := 0; row < cmsDepth; row++ {
+		for i, b := range s.counters[row] {
+			low := (b & 0x0F) >> 1
+			high := (b >> 4) >> 1
+			s.counters[row][i] = (high << 4) | low
+		}
+	}
+}
+
+// bloomFilter是一个简单的bit数组布隆过滤器，用作TinyLFU的doorkeeper
+type bloomFilter struct {
+	bits []byte
+	size int
+}
+
+func newBloomFilter(width int) *bloomFilter {
+	size := width * 8 // 比sketch宽一些，降低误判率
+	if size <= 0 {
+		size = 8
+	}
+	return &bloomFilter{bits: make([]byte, (size+7)/8), size: size}
+}
+
+func (f *bloomFilter) positions(h uint64) (int, int) {
+	p1 := int((h ^ cmsSeeds[0]) % uint64(f.size))
+	p2 := int((h ^ cmsSeeds[1]) % uint64(f.size))
+	return p1, p2
+}
+
+func (f *bloomFilter) test(h uint64) bool {
+	p1, p2 := f.positions(h)
+	return f.bitSet(p1) && f.bitSet(p2)
+}
+
+// testAndSet返回该哈希是否已经在过滤器中出现过，并把对应的bit置位
+func (f *bloomFilter) testAndSet(h uint64) bool {
+	seen := f.test(h)
+	p1, p2 := f.positions(h)
+	f.setBit(p1)
+	f.setBit(p2)
+	return seen
+}
+
+func (f *bloomFilter) bitSet(pos int) bool {
+	return f.bits[pos/8]&(1<<uint(pos%8)) != 0
+}
+
+func (f *bloomFilter) setBit(pos int) {
+	f.bits[pos/8] |= 1 << uint(pos%8)
+}
+
+func (f *bloomFilter) reset() {
+	for i := range f.bits {
+		f.bits[i] = 0
+	}
+}